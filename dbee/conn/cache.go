@@ -8,14 +8,11 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/kndndrj/nvim-dbee/dbee/logging"
+	"github.com/kndndrj/nvim-dbee/dbee/metrics"
 	"github.com/kndndrj/nvim-dbee/dbee/models"
 )
 
-type cacheRecord struct {
-	result  models.Result
-	drained bool
-}
-
 type cacheMap struct {
 	storage sync.Map
 }
@@ -27,7 +24,7 @@ func (cm *cacheMap) store(key string, value cacheRecord) {
 func (cm *cacheMap) load(key string) (cacheRecord, bool) {
 	val, ok := cm.storage.Load(key)
 	if !ok {
-		return cacheRecord{}, false
+		return nil, false
 	}
 
 	return val.(cacheRecord), true
@@ -39,23 +36,162 @@ func (cm *cacheMap) delete(key string) {
 
 // cache maintains a map of currently active results
 // only one result is the active one (the latest one).
-// The non active results stay in the list until they are drained
+// The non active results stay in the list until they are drained.
+//
+// Memory use is bounded by maxMemoryBytes: records are tracked in an LRU,
+// and once the cache-wide estimate crosses the budget, the least recently
+// used non-active records are spilled to spillDir and read back
+// transparently on the next page/Span call.
 type cache struct {
-	active   string
-	records  cacheMap
-	pageSize int
-	log      models.Logger
+	mu             sync.Mutex
+	active         string
+	records        cacheMap
+	lru            *lru
+	pageSize       int
+	maxMemoryBytes int64
+	spillDir       string
+	log            logging.Logger
+	progress       ProgressReporter
 }
 
-func NewCache(pageSize int, logger models.Logger) *cache {
+// NewCache creates a cache that pages results pageSize rows at a time and
+// bounds its in-memory footprint to roughly maxMemoryBytes, spilling
+// anything beyond that to spillDir. progress may be nil, in which case
+// reports are silently dropped.
+func NewCache(pageSize int, maxMemoryBytes int64, spillDir string, logger logging.Logger, progress ProgressReporter) *cache {
+	if progress == nil {
+		progress = noopProgress{}
+	}
+
 	return &cache{
-		pageSize: pageSize,
-		records:  cacheMap{},
-		log:      logger,
+		records:        cacheMap{},
+		lru:            newLRU(),
+		pageSize:       pageSize,
+		maxMemoryBytes: maxMemoryBytes,
+		spillDir:       spillDir,
+		log:            logger,
+		progress:       progress,
+	}
+}
+
+// setActive records id as the currently active record, guarded by c.mu so
+// it can't race with touch()'s locked read or with getActive.
+func (c *cache) setActive(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.active = id
+}
+
+// getActive returns the currently active record's id, guarded by c.mu.
+func (c *cache) getActive() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.active
+}
+
+// touch updates the LRU with rec's current size and evicts older,
+// non-active records to disk until the cache is back under budget.
+func (c *cache) touch(id string, rec cacheRecord) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lru.touch(id, rec.SizeBytes())
+
+	if c.maxMemoryBytes <= 0 {
+		return
+	}
+
+	// Repeatedly scan for an evictable victim (not active, already
+	// drained, and still holding memory) and spill it. Each full scan
+	// that evicts nothing breaks the loop instead of spinning: a record
+	// that's still being produced, or one that's already on disk, will
+	// never become more evictable just by looking at it again.
+	for c.lru.total > c.maxMemoryBytes {
+		evicted := false
+
+		for _, victimID := range c.lru.candidates(c.active) {
+			victim, ok := c.records.load(victimID)
+			if !ok {
+				c.lru.remove(victimID)
+				evicted = true
+				break
+			}
+			if victim.SizeBytes() <= 0 {
+				// already fully spilled, nothing left to reclaim
+				continue
+			}
+			if !victim.Drained() {
+				// still being produced by its own goroutine; evicting it
+				// now would race the producer, so leave it be
+				continue
+			}
+
+			spilled, err := c.spill(victimID, victim)
+			if err != nil {
+				c.log.Error("failed to spill record " + victimID + " to disk: " + err.Error())
+				continue
+			}
+
+			c.records.store(victimID, spilled)
+			c.lru.touch(victimID, spilled.SizeBytes())
+			evicted = true
+			break
+		}
+
+		if !evicted {
+			// nothing left we can safely evict; stay over budget rather
+			// than hang waiting for a candidate that will never appear
+			break
+		}
+	}
+}
+
+// spill converts a fully drained rec to a disk backed record under
+// c.spillDir. Callers must only pass records for which rec.Drained() is
+// true: spilling a record that's still being appended to by its producer
+// goroutine would snapshot a partial result and silently lose any rows
+// written after the snapshot.
+func (c *cache) spill(id string, rec cacheRecord) (cacheRecord, error) {
+	if mr, ok := rec.(*memoryRecord); ok {
+		disk, err := newDiskRecord(c.spillDir, id, mr.Header(), mr.Meta(), mr.snapshot())
+		if err != nil {
+			return nil, err
+		}
+		if err := disk.finishWriting(); err != nil {
+			return nil, err
+		}
+		disk.SetDrained(true)
+		c.log.Debug("spilled cache record " + id + " to disk")
+		return disk, nil
 	}
+
+	// already on disk
+	return rec, nil
 }
 
-func (c *cache) Set(iter models.IterResult) error {
+func (c *cache) evict(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.remove(id)
+}
+
+// spillIfOversized spills rec to disk if it's a memoryRecord over the
+// configured budget. It's only safe to call on a record that's already
+// fully drained, since it takes a one-shot snapshot of its rows.
+func (c *cache) spillIfOversized(id string, rec cacheRecord) (cacheRecord, bool) {
+	if c.maxMemoryBytes <= 0 || rec.SizeBytes() <= c.maxMemoryBytes {
+		return nil, false
+	}
+
+	spilled, err := c.spill(id, rec)
+	if err != nil {
+		c.log.Error("failed to spill oversized record " + id + " to disk: " + err.Error())
+		return nil, false
+	}
+	return spilled, true
+}
+
+func (c *cache) Set(ctx context.Context, iter models.IterResult) error {
 	// close the iterator on error
 	var err error
 	defer func() {
@@ -77,14 +213,15 @@ func (c *cache) Set(iter models.IterResult) error {
 		return err
 	}
 
-	// create a new result
-	result := models.Result{}
-	result.Header = header
-	result.Meta = meta
+	record := newMemoryRecord(header, meta)
 
 	// produce the first page
 	drained := false
 	for i := 0; i < c.pageSize; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		row, err := iter.Next()
 		if err != nil {
 			return err
@@ -95,48 +232,110 @@ func (c *cache) Set(iter models.IterResult) error {
 			break
 		}
 
-		result.Rows = append(result.Rows, row)
+		_ = record.Append(row)
 	}
+	record.SetDrained(drained)
 
 	// create a new record and set it's id as active
 	id := uuid.New().String()
-	c.records.store(id, cacheRecord{
-		result:  result,
-		drained: drained,
-	})
-	c.active = id
+	c.records.store(id, record)
+	c.setActive(id)
+	metrics.CacheActiveRecords.Inc()
+	metrics.CacheRowsProduced.Add(float64(record.Len()))
+
+	// a result that's already complete and over budget can be spilled
+	// immediately: there's no producer goroutine that could race us.
+	if drained {
+		if spilled, ok := c.spillIfOversized(id, record); ok {
+			c.records.store(id, spilled)
+		}
+	}
+	if rec, ok := c.records.load(id); ok {
+		c.touch(id, rec)
+	}
 
 	// process everything else in a separate goroutine
 	if !drained {
 		go func() {
+			start := time.Now()
+			defer func() {
+				metrics.CacheDrainDuration.Observe(time.Since(start).Seconds())
+			}()
+
+			c.progress.Start(id)
+
+			var current cacheRecord = record
+
 			i := 0
 			for {
+				if err := ctx.Err(); err != nil {
+					c.log.Debug("execution aborted, evicting partial record " + id)
+					current.Close()
+					c.records.delete(id)
+					c.evict(id)
+					metrics.CacheActiveRecords.Dec()
+					c.progress.Finish(id, current.Len(), err)
+					return
+				}
+
+				// spill the active record itself once it grows past budget
+				if mr, ok := current.(*memoryRecord); ok && c.maxMemoryBytes > 0 && mr.SizeBytes() > c.maxMemoryBytes {
+					disk, err := newDiskRecord(c.spillDir, id, mr.Header(), mr.Meta(), mr.snapshot())
+					if err != nil {
+						c.log.Error("failed to spill active record " + id + ": " + err.Error())
+					} else {
+						current = disk
+						c.records.store(id, current)
+						c.touch(id, current)
+						c.log.Debug("active record " + id + " exceeded memory budget, spilling to disk")
+					}
+				}
+
 				// update records in chunks
 				if i >= c.pageSize {
-					c.records.store(id, cacheRecord{
-						result: result,
-					})
+					c.records.store(id, current)
+					c.touch(id, current)
+					c.progress.Update(id, current.Len())
 					i = 0
 				}
 				row, err := iter.Next()
 				if err != nil {
-					c.log.Error(err.Error())
+					if errors.Is(err, context.Canceled) {
+						c.log.Debug("execution aborted, evicting partial record " + id)
+					} else {
+						c.log.Error(err.Error())
+					}
+					current.Close()
+					c.records.delete(id)
+					c.evict(id)
+					metrics.CacheActiveRecords.Dec()
+					c.progress.Finish(id, current.Len(), err)
 					return
 				}
 				if row == nil {
 					c.log.Debug("successfully exhausted iterator")
 					break
 				}
-				result.Rows = append(result.Rows, row)
+				if err := current.Append(row); err != nil {
+					c.log.Error(err.Error())
+					break
+				}
+				metrics.CacheRowsProduced.Inc()
 				i++
 			}
 
 			// store one last time and set drained to true
-			c.records.store(id, cacheRecord{
-				drained: true,
-				result:  result,
-			})
+			if dr, ok := current.(*diskRecord); ok {
+				_ = dr.finishWriting()
+			}
+			current.SetDrained(true)
+			c.records.store(id, current)
+			c.touch(id, current)
+			metrics.CacheActiveRecords.Dec()
+			c.progress.Finish(id, current.Len(), nil)
 		}()
+	} else {
+		metrics.CacheActiveRecords.Dec()
 	}
 
 	return nil
@@ -146,18 +345,15 @@ func (c *cache) Set(iter models.IterResult) error {
 // returns current page and total number of pages
 // writes the requested page to outputs
 func (c *cache) page(page int, outputs ...Output) (int, int, error) {
-	id := c.active
+	metrics.CachePageRequests.Inc()
 
-	cr, _ := c.records.load(id)
-	cachedResult := cr.result
+	id := c.getActive()
 
-	if cachedResult.Header == nil {
+	rec, ok := c.records.load(id)
+	if !ok || rec.Header() == nil {
 		return 0, 0, errors.New("no results to page")
 	}
-
-	var result models.Result
-	result.Header = cachedResult.Header
-	result.Meta = cachedResult.Meta
+	c.touch(id, rec)
 
 	if page < 0 {
 		page = 0
@@ -166,7 +362,7 @@ func (c *cache) page(page int, outputs ...Output) (int, int, error) {
 	start := c.pageSize * page
 	end := c.pageSize * (page + 1)
 
-	l := len(cachedResult.Rows)
+	l := rec.Len()
 	lastPage := l / c.pageSize
 	if l%c.pageSize == 0 && lastPage != 0 {
 		lastPage -= 1
@@ -179,7 +375,15 @@ func (c *cache) page(page int, outputs ...Output) (int, int, error) {
 		end = l
 	}
 
-	result.Rows = cachedResult.Rows[start:end]
+	rows, err := rec.Rows(start, end)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var result models.Result
+	result.Header = rec.Header()
+	result.Meta = rec.Meta()
+	result.Rows = rows
 	result.Meta.ChunkStart = start
 
 	// write the page to outputs
@@ -205,7 +409,12 @@ var ErrInvalidRange = func(from int, to int) error { return fmt.Errorf("invalid
 //
 // outputs - where to pipe the results
 func (c *cache) Span(from int, to int, wipe bool, outputs ...Output) error {
-	id := c.active
+	start := time.Now()
+	defer func() {
+		metrics.CacheSpanDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	id := c.getActive()
 
 	// validation
 	if (from < 0 && to < 0) || (from >= 0 && to >= 0) {
@@ -218,7 +427,7 @@ func (c *cache) Span(from int, to int, wipe bool, outputs ...Output) error {
 		return ErrInvalidRange(from, to)
 	}
 
-	var cachedResult models.Result
+	var rec cacheRecord
 
 	// timeout context
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
@@ -226,13 +435,13 @@ func (c *cache) Span(from int, to int, wipe bool, outputs ...Output) error {
 
 	// Wait for drain, available index or timeout
 	for {
-		rec, ok := c.records.load(id)
+		r, ok := c.records.load(id)
 		if !ok {
 			return fmt.Errorf("record %s appears to be already flushed", id)
 		}
 
-		if rec.drained || (to >= 0 && to <= len(rec.result.Rows)) {
-			cachedResult = rec.result
+		if r.Drained() || (to >= 0 && to <= r.Len()) {
+			rec = r
 			break
 		}
 
@@ -241,10 +450,11 @@ func (c *cache) Span(from int, to int, wipe bool, outputs ...Output) error {
 		}
 		time.Sleep(1 * time.Second)
 	}
+	c.touch(id, rec)
 
 	// calculate range
 
-	length := len(cachedResult.Rows)
+	length := rec.Len()
 	if from < 0 {
 		from += length
 		if from < 0 {
@@ -265,12 +475,16 @@ func (c *cache) Span(from int, to int, wipe bool, outputs ...Output) error {
 		to = length
 	}
 
+	rows, err := rec.Rows(from, to)
+	if err != nil {
+		return err
+	}
+
 	// create a new page
 	var result models.Result
-	result.Header = cachedResult.Header
-	result.Meta = cachedResult.Meta
-
-	result.Rows = cachedResult.Rows[from:to]
+	result.Header = rec.Header()
+	result.Meta = rec.Meta()
+	result.Rows = rows
 	result.Meta.ChunkStart = from
 
 	// write the page to outputs
@@ -283,7 +497,9 @@ func (c *cache) Span(from int, to int, wipe bool, outputs ...Output) error {
 
 	// delete the record from cache
 	if wipe {
+		rec.Close()
 		c.records.delete(id)
+		c.evict(id)
 		c.log.Debug("successfully wiped record from cache")
 	}
 
@@ -294,7 +510,7 @@ func (c *cache) Span(from int, to int, wipe bool, outputs ...Output) error {
 // flush writes the whole current cache to outputs
 // wipe controls wheather to wipe the record from cache
 func (c *cache) flush(wipe bool, outputs ...Output) {
-	id := c.active
+	id := c.getActive()
 
 	// wait until the currently active record is drained,
 	// write it to outputs and remove it from records
@@ -309,7 +525,7 @@ func (c *cache) flush(wipe bool, outputs ...Output) {
 				c.log.Error("record " + id + " appears to be already flushed")
 				return
 			}
-			if rec.drained {
+			if rec.Drained() {
 				break
 			}
 			if ctx.Err() != nil {
@@ -322,15 +538,29 @@ func (c *cache) flush(wipe bool, outputs ...Output) {
 		// write to outputs
 		for _, out := range outputs {
 			rec, _ := c.records.load(id)
-			err := out.Write(rec.result)
+			rows, err := rec.Rows(0, rec.Len())
 			if err != nil {
 				c.log.Error(err.Error())
+				continue
+			}
+
+			var result models.Result
+			result.Header = rec.Header()
+			result.Meta = rec.Meta()
+			result.Rows = rows
+
+			if err := out.Write(result); err != nil {
+				c.log.Error(err.Error())
 			}
 		}
 
 		if wipe {
+			if rec, ok := c.records.load(id); ok {
+				rec.Close()
+			}
 			// delete the record
 			c.records.delete(id)
+			c.evict(id)
 			c.log.Debug("successfully wiped record from cache")
 		}
 		c.log.Debug("successfully flushed cache")
@@ -0,0 +1,133 @@
+package conn
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kndndrj/nvim-dbee/dbee/logging"
+	"github.com/kndndrj/nvim-dbee/dbee/models"
+)
+
+// fakeIter is a models.IterResult that yields a fixed number of generated
+// rows, optionally pausing between them so a test can force two Set calls
+// to overlap in time.
+type fakeIter struct {
+	header models.Header
+	rows   int
+	delay  time.Duration
+	next   int
+	closed bool
+}
+
+func newFakeIter(rows int, delay time.Duration) *fakeIter {
+	return &fakeIter{
+		header: models.Header{"id", "value"},
+		rows:   rows,
+		delay:  delay,
+	}
+}
+
+func (f *fakeIter) Header() (models.Header, error) { return f.header, nil }
+func (f *fakeIter) Meta() (models.Meta, error)     { return models.Meta{}, nil }
+
+func (f *fakeIter) Next() (models.Row, error) {
+	if f.next >= f.rows {
+		return nil, nil
+	}
+	if f.delay > 0 {
+		time.Sleep(f.delay)
+	}
+	row := models.Row{f.next, fmt.Sprintf("row-%d-padding-%s", f.next, longPadding)}
+	f.next++
+	return row, nil
+}
+
+func (f *fakeIter) Close() { f.closed = true }
+
+// longPadding pads each row so a handful of rows is enough to cross a tiny
+// test memory budget without needing thousands of iterations.
+const longPadding = "0123456789012345678901234567890123456789012345678901234567890123456789"
+
+// waitDrained polls until id's record reports drained, failing the test if
+// it doesn't happen before timeout - this is what catches the eviction
+// loop hanging forever while holding c.mu.
+func waitDrained(t *testing.T, c *cache, id string, timeout time.Duration) cacheRecord {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		rec, ok := c.records.load(id)
+		if ok && rec.Drained() {
+			return rec
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("record %s did not drain within %s (possible deadlock)", id, timeout)
+	return nil
+}
+
+// TestCacheEvictionUnderTinyBudget drives two overlapping queries through a
+// cache configured with a memory budget small enough that both records
+// outgrow it and must end up spilled to disk, with the first query still
+// draining after the second one has taken over as active. It
+// guards against two regressions: the eviction loop spinning forever once
+// a record is already on disk or still being produced, and the evictor
+// spilling a record that its own producer goroutine is still appending to.
+func TestCacheEvictionUnderTinyBudget(t *testing.T) {
+	dir := t.TempDir()
+	c := NewCache(2, 256, dir, logging.DiscardLogger{}, nil)
+
+	ctx := context.Background()
+
+	// first query: slow enough that it's still draining when the second
+	// one starts and becomes the new active record.
+	firstIter := newFakeIter(20, 5*time.Millisecond)
+	if err := c.Set(ctx, firstIter); err != nil {
+		t.Fatalf("first Set failed: %v", err)
+	}
+	firstID := c.active
+
+	// second query supersedes the first as active before it's done
+	// draining, which is exactly the scenario the eviction loop must
+	// handle without racing the first query's producer goroutine.
+	secondIter := newFakeIter(20, 0)
+	if err := c.Set(ctx, secondIter); err != nil {
+		t.Fatalf("second Set failed: %v", err)
+	}
+	secondID := c.active
+
+	if firstID == secondID {
+		t.Fatalf("expected distinct record ids, got the same id twice")
+	}
+
+	firstRec := waitDrained(t, c, firstID, 2*time.Second)
+	secondRec := waitDrained(t, c, secondID, 2*time.Second)
+
+	if got := firstRec.Len(); got != 20 {
+		t.Errorf("first record: expected 20 rows to survive eviction, got %d", got)
+	}
+	if got := secondRec.Len(); got != 20 {
+		t.Errorf("second record: expected 20 rows, got %d", got)
+	}
+
+	// the superseded record should have been spilled to disk by now,
+	// freeing its reported in-memory footprint.
+	if firstRec.SizeBytes() != 0 {
+		t.Errorf("expected first record to be spilled to disk, still reports %d bytes", firstRec.SizeBytes())
+	}
+
+	// a final page call must return promptly - if eviction ever left
+	// c.mu locked, this would hang.
+	done := make(chan struct{})
+	go func() {
+		_, _, _ = c.page(0)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("cache.page did not return - eviction likely left the cache locked")
+	}
+}
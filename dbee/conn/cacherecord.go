@@ -0,0 +1,255 @@
+package conn
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/kndndrj/nvim-dbee/dbee/models"
+)
+
+// cacheRecord is a materialized query result. It starts out as a
+// memoryRecord and, once it grows past the cache's memory budget, is
+// converted to a diskRecord that spills further (and eventually all) rows
+// to a temp file, so a single long-running analytics session doesn't grow
+// without bound.
+type cacheRecord interface {
+	Header() models.Header
+	Meta() models.Meta
+	Drained() bool
+	SetDrained(bool)
+	Len() int
+	// Rows returns the rows in [from, to), reading them back from wherever
+	// they're currently stored.
+	Rows(from, to int) ([]models.Row, error)
+	Append(row models.Row) error
+	// SizeBytes is an approximate in-memory footprint, used by the cache's
+	// LRU eviction budget. A fully spilled record reports 0.
+	SizeBytes() int64
+	// Close releases any on-disk resources held by the record.
+	Close() error
+}
+
+// estimateRowBytes gives a rough size estimate for a row, good enough to
+// drive the memory budget without the overhead of actually encoding it.
+func estimateRowBytes(row models.Row) int64 {
+	var n int64
+	for _, cell := range row {
+		n += int64(len(fmt.Sprintf("%v", cell))) + 16 // + per-cell overhead
+	}
+	return n
+}
+
+type memoryRecord struct {
+	mu      sync.RWMutex
+	header  models.Header
+	meta    models.Meta
+	rows    []models.Row
+	size    int64
+	drained bool
+}
+
+func newMemoryRecord(header models.Header, meta models.Meta) *memoryRecord {
+	return &memoryRecord{header: header, meta: meta}
+}
+
+func (r *memoryRecord) Header() models.Header { return r.header }
+func (r *memoryRecord) Meta() models.Meta     { return r.meta }
+
+func (r *memoryRecord) Drained() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.drained
+}
+
+func (r *memoryRecord) SetDrained(drained bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.drained = drained
+}
+
+func (r *memoryRecord) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.rows)
+}
+
+func (r *memoryRecord) Rows(from, to int) ([]models.Row, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if from < 0 || to > len(r.rows) || from > to {
+		return nil, fmt.Errorf("row range [%d, %d) out of bounds (len %d)", from, to, len(r.rows))
+	}
+	return r.rows[from:to], nil
+}
+
+func (r *memoryRecord) Append(row models.Row) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rows = append(r.rows, row)
+	r.size += estimateRowBytes(row)
+	return nil
+}
+
+func (r *memoryRecord) SizeBytes() int64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.size
+}
+
+func (r *memoryRecord) Close() error { return nil }
+
+// snapshot returns the rows accumulated so far, for handing off to a
+// diskRecord during a spill.
+func (r *memoryRecord) snapshot() []models.Row {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]models.Row, len(r.rows))
+	copy(out, r.rows)
+	return out
+}
+
+// diskRecord stores its rows as a sequence of gob-encoded values in a temp
+// file under the cache's spill directory. While a query is still producing
+// rows, enc/file stay open so Append can keep streaming new rows straight
+// to disk instead of buffering them.
+type diskRecord struct {
+	mu      sync.Mutex
+	header  models.Header
+	meta    models.Meta
+	path    string
+	count   int
+	drained bool
+	file    *os.File
+	enc     *gob.Encoder
+}
+
+// newDiskRecord creates the spill file for id under dir and writes rows
+// (the portion of the record already accumulated in memory) into it. The
+// file is left open for further Append calls until finishWriting is called.
+func newDiskRecord(dir, id string, header models.Header, meta models.Meta, rows []models.Row) (*diskRecord, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, id+".cache")
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &diskRecord{
+		header: header,
+		meta:   meta,
+		path:   path,
+		file:   f,
+		enc:    gob.NewEncoder(f),
+	}
+
+	for _, row := range rows {
+		if err := r.enc.Encode(&row); err != nil {
+			f.Close()
+			return nil, err
+		}
+		r.count++
+	}
+
+	return r, nil
+}
+
+func (r *diskRecord) Header() models.Header { return r.header }
+func (r *diskRecord) Meta() models.Meta     { return r.meta }
+
+func (r *diskRecord) Drained() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.drained
+}
+
+func (r *diskRecord) SetDrained(drained bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.drained = drained
+}
+
+func (r *diskRecord) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.count
+}
+
+// Append streams row straight to the spill file. It's only valid to call
+// this while the record hasn't finished writing yet (i.e. it's still the
+// active, spilled-to-disk record of an in-progress query).
+func (r *diskRecord) Append(row models.Row) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.enc == nil {
+		return fmt.Errorf("cannot append to record %s: spill file already closed", r.path)
+	}
+
+	if err := r.enc.Encode(&row); err != nil {
+		return err
+	}
+	r.count++
+	return nil
+}
+
+// finishWriting closes the writer side of the spill file. Rows can still be
+// read back afterwards via Rows.
+func (r *diskRecord) finishWriting() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	r.enc = nil
+	return err
+}
+
+// Rows reads back rows [from, to) by decoding the spill file from the
+// start. It's O(to) per call, which is acceptable since pages are small
+// relative to the whole result. It takes r.mu for the duration of the read
+// so it can't race with a concurrent Append still streaming to the same
+// file while the query is in progress.
+func (r *diskRecord) Rows(from, to int) ([]models.Row, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if from < 0 || from > to {
+		return nil, fmt.Errorf("row range [%d, %d) is invalid", from, to)
+	}
+
+	f, err := os.Open(r.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dec := gob.NewDecoder(f)
+
+	out := make([]models.Row, 0, to-from)
+	for i := 0; i < to; i++ {
+		var row models.Row
+		if err := dec.Decode(&row); err != nil {
+			return nil, fmt.Errorf("reading spilled row %d of %s: %w", i, r.path, err)
+		}
+		if i >= from {
+			out = append(out, row)
+		}
+	}
+	return out, nil
+}
+
+func (r *diskRecord) SizeBytes() int64 { return 0 }
+
+func (r *diskRecord) Close() error {
+	_ = r.finishWriting()
+	return os.Remove(r.path)
+}
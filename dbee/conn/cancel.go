@@ -0,0 +1,72 @@
+package conn
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// cancelRegistry keeps track of the cancel function for every query
+// currently executing on a given connection id, so that a Dbee_cancel call
+// from Neovim can reach across the goroutine boundary and abort it. A
+// connection can have more than one query in flight at once (the cache
+// happily keeps multiple non-active records draining in the background),
+// so cancel functions are tracked per execution, not one-per-connection.
+type cancelRegistry struct {
+	mu      sync.Mutex
+	cancels map[string]map[string]func()
+}
+
+// Cancels is the package level registry used by the Dbee_execute and
+// Dbee_cancel plugin handlers.
+var Cancels = &cancelRegistry{
+	cancels: make(map[string]map[string]func()),
+}
+
+// Register stores cancel under a fresh execution id for connection id,
+// returning that execution id so the caller can Clear it when the query
+// finishes.
+func (r *cancelRegistry) Register(id string, cancel func()) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	execID := uuid.New().String()
+	if r.cancels[id] == nil {
+		r.cancels[id] = make(map[string]func())
+	}
+	r.cancels[id][execID] = cancel
+	return execID
+}
+
+// Clear removes a single execution's cancel function, leaving any other
+// queries still running on the same connection id untouched.
+func (r *cancelRegistry) Clear(id string, execID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	execs, ok := r.cancels[id]
+	if !ok {
+		return
+	}
+	delete(execs, execID)
+	if len(execs) == 0 {
+		delete(r.cancels, id)
+	}
+}
+
+// Cancel invokes and removes every cancel function currently registered
+// for connection id, returning false if none were running.
+func (r *cancelRegistry) Cancel(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	execs, ok := r.cancels[id]
+	if !ok || len(execs) == 0 {
+		return false
+	}
+	for _, cancel := range execs {
+		cancel()
+	}
+	delete(r.cancels, id)
+	return true
+}
@@ -0,0 +1,60 @@
+package conn
+
+import "container/list"
+
+// lru tracks approximate in-memory byte usage per cached record and which
+// ones were touched least recently, so the cache can decide what to spill
+// to disk first once it crosses its memory budget. The active record is
+// never picked as a victim by the caller (see cache.touch).
+type lru struct {
+	order *list.List
+	elems map[string]*list.Element
+	sizes map[string]int64
+	total int64
+}
+
+func newLRU() *lru {
+	return &lru{
+		order: list.New(),
+		elems: make(map[string]*list.Element),
+		sizes: make(map[string]int64),
+	}
+}
+
+// touch marks id as most recently used and records its current size,
+// adjusting the running total.
+func (l *lru) touch(id string, size int64) {
+	l.total += size - l.sizes[id]
+	l.sizes[id] = size
+
+	if elem, ok := l.elems[id]; ok {
+		l.order.MoveToFront(elem)
+		return
+	}
+	l.elems[id] = l.order.PushFront(id)
+}
+
+// remove drops id from tracking entirely.
+func (l *lru) remove(id string) {
+	if elem, ok := l.elems[id]; ok {
+		l.order.Remove(elem)
+		delete(l.elems, id)
+	}
+	l.total -= l.sizes[id]
+	delete(l.sizes, id)
+}
+
+// candidates returns tracked ids other than exclude, ordered from least to
+// most recently used. The caller is expected to walk this list looking for
+// the first one that's actually safe to evict (e.g. already drained, still
+// holding memory) rather than assuming the first entry always qualifies.
+func (l *lru) candidates(exclude string) []string {
+	ids := make([]string, 0, len(l.elems))
+	for elem := l.order.Back(); elem != nil; elem = elem.Prev() {
+		id := elem.Value.(string)
+		if id != exclude {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,107 @@
+package conn
+
+import (
+	"sync"
+	"time"
+
+	"github.com/neovim/go-client/nvim"
+)
+
+// ProgressReporter receives row-count updates while a query result is being
+// streamed into the cache, so that callers can surface progress for queries
+// that take a while to drain.
+type ProgressReporter interface {
+	// Start is called once, right before the producer goroutine begins
+	// reading rows for id.
+	Start(id string)
+	// Update is called periodically with the number of rows read so far.
+	Update(id string, rows int)
+	// Finish is called once the iterator is drained or returns an error.
+	// err is nil on a clean drain.
+	Finish(id string, total int, err error)
+}
+
+// noopProgress is the default ProgressReporter and does nothing. It keeps
+// cache usable without a reporter wired in.
+type noopProgress struct{}
+
+func (noopProgress) Start(id string)                        {}
+func (noopProgress) Update(id string, rows int)             {}
+func (noopProgress) Finish(id string, total int, err error) {}
+
+// progressThrottle is the minimum interval between two Update calls reaching
+// the Lua side for the same id.
+const progressThrottle = 100 * time.Millisecond
+
+// NvimProgress reports progress by calling a Lua function registered via
+// Dbee_set_progress_callback, e.g. require('dbee.progress').update(id, rows).
+// Updates are throttled per id so a fast producer doesn't flood the UI.
+type NvimProgress struct {
+	v        *nvim.Nvim
+	luaFn    string
+	mu       sync.Mutex
+	lastSent map[string]time.Time
+}
+
+// NewNvimProgress creates a NvimProgress that calls luaFn, a fully qualified
+// Lua expression such as "require('dbee.progress').update".
+func NewNvimProgress(v *nvim.Nvim, luaFn string) *NvimProgress {
+	return &NvimProgress{
+		v:        v,
+		luaFn:    luaFn,
+		lastSent: make(map[string]time.Time),
+	}
+}
+
+// SetCallback updates the Lua function called on every report.
+func (p *NvimProgress) SetCallback(luaFn string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.luaFn = luaFn
+}
+
+func (p *NvimProgress) call(id string, rows int, total int, finished bool, errMsg string) {
+	p.mu.Lock()
+	luaFn := p.luaFn
+	p.mu.Unlock()
+
+	if luaFn == "" {
+		return
+	}
+
+	lua := luaFn + "(...)"
+	_ = p.v.ExecLua(lua, nil, id, rows, total, finished, errMsg)
+}
+
+func (p *NvimProgress) Start(id string) {
+	p.mu.Lock()
+	p.lastSent[id] = time.Time{}
+	p.mu.Unlock()
+	p.call(id, 0, 0, false, "")
+}
+
+func (p *NvimProgress) Update(id string, rows int) {
+	p.mu.Lock()
+	last, ok := p.lastSent[id]
+	now := time.Now()
+	if ok && now.Sub(last) < progressThrottle {
+		p.mu.Unlock()
+		return
+	}
+	p.lastSent[id] = now
+	p.mu.Unlock()
+
+	p.call(id, rows, 0, false, "")
+}
+
+func (p *NvimProgress) Finish(id string, total int, err error) {
+	p.mu.Lock()
+	delete(p.lastSent, id)
+	p.mu.Unlock()
+
+	msg := ""
+	if err != nil {
+		msg = err.Error()
+	}
+	p.call(id, total, total, true, msg)
+}
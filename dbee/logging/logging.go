@@ -0,0 +1,86 @@
+package logging
+
+import "sync"
+
+// Logger is the structured logging interface used across the plugin. It
+// replaces the old Debug/Error-only models.Logger with proper leveling, so
+// Debug output can be silenced in production without losing Error visibility.
+type Logger interface {
+	Trace(msg string)
+	Debug(msg string)
+	Info(msg string)
+	Warn(msg string)
+	Error(msg string)
+	Fatal(msg string)
+}
+
+// MultiLogger fans a single log call out to any number of sinks. A message
+// is checked once against the logger's single current level; every sink
+// receives the messages that pass.
+type MultiLogger struct {
+	mu    sync.RWMutex
+	level Level
+	sinks []Logger
+}
+
+// NewMultiLogger builds a MultiLogger writing to all of the given sinks at
+// the given starting level.
+func NewMultiLogger(level Level, sinks ...Logger) *MultiLogger {
+	m := &MultiLogger{sinks: sinks}
+	m.SetLevel(level)
+	return m
+}
+
+// SetSinks replaces the set of sinks messages fan out to.
+func (m *MultiLogger) SetSinks(sinks ...Logger) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sinks = sinks
+}
+
+// SetLevel changes the minimum level at runtime.
+func (m *MultiLogger) SetLevel(level Level) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.level = level
+}
+
+func (m *MultiLogger) currentLevel() Level {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.level
+}
+
+func (m *MultiLogger) log(level Level, msg string) {
+	if level < m.currentLevel() {
+		return
+	}
+
+	m.mu.RLock()
+	sinks := m.sinks
+	m.mu.RUnlock()
+
+	for _, s := range sinks {
+		switch level {
+		case LevelTrace:
+			s.Trace(msg)
+		case LevelDebug:
+			s.Debug(msg)
+		case LevelInfo:
+			s.Info(msg)
+		case LevelWarn:
+			s.Warn(msg)
+		case LevelError:
+			s.Error(msg)
+		case LevelFatal:
+			s.Fatal(msg)
+		}
+	}
+}
+
+func (m *MultiLogger) Trace(msg string) { m.log(LevelTrace, msg) }
+func (m *MultiLogger) Debug(msg string) { m.log(LevelDebug, msg) }
+func (m *MultiLogger) Info(msg string)  { m.log(LevelInfo, msg) }
+func (m *MultiLogger) Warn(msg string)  { m.log(LevelWarn, msg) }
+func (m *MultiLogger) Error(msg string) { m.log(LevelError, msg) }
+func (m *MultiLogger) Fatal(msg string) { m.log(LevelFatal, msg) }
@@ -0,0 +1,12 @@
+package logging
+
+// DiscardLogger is a Logger that drops every message. It's the sink used in
+// tests and anywhere log output would just be noise.
+type DiscardLogger struct{}
+
+func (DiscardLogger) Trace(msg string) {}
+func (DiscardLogger) Debug(msg string) {}
+func (DiscardLogger) Info(msg string)  {}
+func (DiscardLogger) Warn(msg string)  {}
+func (DiscardLogger) Error(msg string) {}
+func (DiscardLogger) Fatal(msg string) {}
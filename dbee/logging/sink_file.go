@@ -0,0 +1,167 @@
+package logging
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FileSink writes leveled, timestamped lines to a file, rotating it once it
+// crosses maxSizeBytes. Rotated files are gzipped immediately and only the
+// maxBackups most recent ones are kept.
+type FileSink struct {
+	mu          sync.Mutex
+	path        string
+	maxSize     int64
+	maxBackups  int
+	file        *os.File
+	currentSize int64
+}
+
+// NewFileSink opens (creating if needed) the log file at path, rotating at
+// maxSizeBytes and keeping at most maxBackups gzipped rotations. A
+// maxSizeBytes <= 0 never rotates, and a maxBackups <= 0 keeps every
+// rotation.
+func NewFileSink(path string, maxSizeBytes int64, maxBackups int) (*FileSink, error) {
+	f := &FileSink{
+		path:       path,
+		maxSize:    maxSizeBytes,
+		maxBackups: maxBackups,
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *FileSink) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.currentSize = info.Size()
+	return nil
+}
+
+func (f *FileSink) write(level Level, msg string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return
+	}
+
+	line := fmt.Sprintf("%s [%s] %s\n", time.Now().Format(time.RFC3339), level, msg)
+	n, err := f.file.WriteString(line)
+	if err != nil {
+		return
+	}
+	f.currentSize += int64(n)
+
+	if f.maxSize > 0 && f.currentSize >= f.maxSize {
+		_ = f.rotate()
+	}
+}
+
+// rotate closes the current file, gzips it into a timestamped backup, starts
+// a fresh file, and prunes backups beyond maxBackups. Caller must hold f.mu.
+func (f *FileSink) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+
+	rotated := fmt.Sprintf("%s.%s", f.path, time.Now().Format("20060102T150405"))
+	if err := os.Rename(f.path, rotated); err != nil {
+		return err
+	}
+	if err := gzipFile(rotated); err != nil {
+		return err
+	}
+
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	return f.pruneBackups()
+}
+
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(path + ".gz")
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	gw := gzip.NewWriter(dst)
+	if _, err := io.Copy(gw, src); err != nil {
+		gw.Close()
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+func (f *FileSink) pruneBackups() error {
+	if f.maxBackups <= 0 {
+		return nil
+	}
+
+	matches, err := filepath.Glob(f.path + ".*.gz")
+	if err != nil {
+		return err
+	}
+	if len(matches) <= f.maxBackups {
+		return nil
+	}
+
+	sort.Strings(matches)
+	stale := matches[:len(matches)-f.maxBackups]
+	for _, m := range stale {
+		_ = os.Remove(m)
+	}
+	return nil
+}
+
+// Close flushes and closes the underlying file.
+func (f *FileSink) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func (f *FileSink) Trace(msg string) { f.write(LevelTrace, msg) }
+func (f *FileSink) Debug(msg string) { f.write(LevelDebug, msg) }
+func (f *FileSink) Info(msg string)  { f.write(LevelInfo, msg) }
+func (f *FileSink) Warn(msg string)  { f.write(LevelWarn, msg) }
+func (f *FileSink) Error(msg string) { f.write(LevelError, msg) }
+func (f *FileSink) Fatal(msg string) { f.write(LevelFatal, msg) }
@@ -0,0 +1,32 @@
+package logging
+
+import "github.com/neovim/go-client/nvim"
+
+// NvimSink echoes messages into the Neovim message area, mirroring the
+// behaviour of the old nvimlog.Logger. Higher levels are rendered in the
+// corresponding highlight group so errors stand out.
+type NvimSink struct {
+	v *nvim.Nvim
+}
+
+// NewNvimSink creates a sink that writes to v via nvim_echo.
+func NewNvimSink(v *nvim.Nvim) *NvimSink {
+	return &NvimSink{v: v}
+}
+
+// echo renders msg via nvim_echo, passing it as a real RPC argument instead
+// of interpolating it into an ex command string - a logged message can
+// contain arbitrary text (e.g. a SQL error echoing back part of the query),
+// and string-building a Command would let a quote in msg break out into
+// executing an arbitrary ex command.
+func (s *NvimSink) echo(hl, msg string) {
+	chunks := []interface{}{[]interface{}{"[dbee] " + msg, hl}}
+	_ = s.v.Call("nvim_echo", nil, chunks, true, map[string]interface{}{})
+}
+
+func (s *NvimSink) Trace(msg string) { s.echo("Comment", msg) }
+func (s *NvimSink) Debug(msg string) { s.echo("Comment", msg) }
+func (s *NvimSink) Info(msg string)  { s.echo("None", msg) }
+func (s *NvimSink) Warn(msg string)  { s.echo("WarningMsg", msg) }
+func (s *NvimSink) Error(msg string) { s.echo("ErrorMsg", msg) }
+func (s *NvimSink) Fatal(msg string) { s.echo("ErrorMsg", msg) }
@@ -1,13 +1,15 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"strconv"
 	"time"
 
 	"github.com/kndndrj/nvim-dbee/dbee/clients"
 	"github.com/kndndrj/nvim-dbee/dbee/conn"
-	"github.com/kndndrj/nvim-dbee/dbee/nvimlog"
+	"github.com/kndndrj/nvim-dbee/dbee/logging"
+	"github.com/kndndrj/nvim-dbee/dbee/metrics"
 	"github.com/kndndrj/nvim-dbee/dbee/output"
 	"github.com/kndndrj/nvim-dbee/dbee/output/format"
 	"github.com/neovim/go-client/nvim"
@@ -31,10 +33,16 @@ func main() {
 	}()
 
 	plugin.Main(func(p *plugin.Plugin) error {
-		logger := nvimlog.New(p.Nvim)
+		nvimSink := logging.NewNvimSink(p.Nvim)
+		logger := logging.NewMultiLogger(logging.LevelDebug, nvimSink)
+
+		// holds the file sink configured via Dbee_configure_logging, if any
+		var fileSink *logging.FileSink
 
 		deferer(func() {
-			logger.Close()
+			if fileSink != nil {
+				fileSink.Close()
+			}
 		})
 
 		// Call clients from lua via id (string)
@@ -48,6 +56,165 @@ func main() {
 
 		bufferOutput := output.NewBuffer(p.Nvim, format.NewTable())
 
+		var metricsServer *metrics.Server
+
+		deferer(func() {
+			if metricsServer != nil {
+				_ = metricsServer.Stop(context.Background())
+			}
+		})
+
+		p.HandleFunction(&plugin.FunctionOptions{Name: "Dbee_start_metrics_server"},
+			func(args []string) (string, error) {
+				method := "Dbee_start_metrics_server"
+				logger.Debug("calling " + method)
+				if len(args) < 1 {
+					logger.Error("not enough arguments passed to " + method)
+					return "", nil
+				}
+
+				if metricsServer != nil {
+					logger.Debug("metrics server already running")
+					return "", nil
+				}
+
+				srv, resolvedAddr, err := metrics.Start(args[0])
+				if err != nil {
+					logger.Error(err.Error())
+					return "", nil
+				}
+				metricsServer = srv
+
+				logger.Debug(method + " returned successfully")
+				return resolvedAddr, nil
+			})
+
+		p.HandleFunction(&plugin.FunctionOptions{Name: "Dbee_stop_metrics_server"},
+			func(args []string) error {
+				method := "Dbee_stop_metrics_server"
+				logger.Debug("calling " + method)
+
+				if metricsServer == nil {
+					logger.Debug("no metrics server running")
+					return nil
+				}
+
+				if err := metricsServer.Stop(context.Background()); err != nil {
+					logger.Error(err.Error())
+					return nil
+				}
+				metricsServer = nil
+
+				logger.Debug(method + " returned successfully")
+				return nil
+			})
+
+		progressReporter := conn.NewNvimProgress(p.Nvim, "")
+
+		p.HandleFunction(&plugin.FunctionOptions{Name: "Dbee_configure_logging"},
+			func(args []string) error {
+				method := "Dbee_configure_logging"
+				logger.Debug("calling " + method)
+				if len(args) < 1 {
+					logger.Error("not enough arguments passed to " + method)
+					return nil
+				}
+
+				level := args[0]
+				logger.SetLevel(logging.ParseLevel(level))
+
+				sinks := []logging.Logger{nvimSink}
+
+				// optional: path, max size (bytes), max backups
+				if len(args) >= 2 && args[1] != "" {
+					path := args[1]
+
+					maxSize := int64(10 * 1024 * 1024)
+					if len(args) >= 3 {
+						if parsed, err := strconv.ParseInt(args[2], 10, 64); err == nil {
+							maxSize = parsed
+						}
+					}
+
+					maxBackups := 5
+					if len(args) >= 4 {
+						if parsed, err := strconv.Atoi(args[3]); err == nil {
+							maxBackups = parsed
+						}
+					}
+
+					if fileSink != nil {
+						fileSink.Close()
+					}
+
+					sink, err := logging.NewFileSink(path, maxSize, maxBackups)
+					if err != nil {
+						logger.Error(err.Error())
+						return nil
+					}
+					fileSink = sink
+				}
+
+				// keep any already-configured file sink even when this call
+				// didn't pass a path (e.g. a later call just bumping the
+				// log level), instead of silently dropping file logging.
+				if fileSink != nil {
+					sinks = append(sinks, fileSink)
+				}
+
+				logger.SetSinks(sinks...)
+
+				logger.Debug(method + " returned successfully")
+				return nil
+			})
+
+		p.HandleFunction(&plugin.FunctionOptions{Name: "Dbee_set_progress_callback"},
+			func(args []string) error {
+				method := "Dbee_set_progress_callback"
+				logger.Debug("calling " + method)
+				if len(args) < 1 {
+					logger.Error("not enough arguments passed to " + method)
+					return nil
+				}
+
+				progressReporter.SetCallback(args[0])
+
+				logger.Debug(method + " returned successfully")
+				return nil
+			})
+
+		p.HandleFunction(&plugin.FunctionOptions{Name: "Dbee_configure_cache"},
+			func(args []string) error {
+				method := "Dbee_configure_cache"
+				logger.Debug("calling " + method)
+				if len(args) < 3 {
+					logger.Error("not enough arguments passed to " + method)
+					return nil
+				}
+
+				id := args[0]
+				maxMemoryBytes, err := strconv.ParseInt(args[1], 10, 64)
+				if err != nil {
+					logger.Error(err.Error())
+					return nil
+				}
+				spillDir := args[2]
+
+				c, ok := connections[id]
+				if !ok {
+					logger.Error("connection with id " + id + " not registered")
+					return nil
+				}
+
+				if err := c.ConfigureCache(maxMemoryBytes, spillDir); err != nil {
+					logger.Error(err.Error())
+					return nil
+				}
+
+				logger.Debug(method + " returned successfully")
+				return nil
+			})
+
 		// Control the results window
 		// This must be called before bufferOutput is used
 		p.HandleFunction(&plugin.FunctionOptions{Name: "Dbee_set_results_buf"},
@@ -92,7 +259,7 @@ func main() {
 
 				h := conn.NewHistory(id, logger)
 
-				c := conn.New(client, pageSize, h, logger)
+				c := conn.New(client, pageSize, h, logger, progressReporter)
 
 				connections[id] = c
 
@@ -119,9 +286,21 @@ func main() {
 					return nil
 				}
 
+				ctx, cancel := context.WithCancel(context.Background())
+				execID := conn.Cancels.Register(id, cancel)
+
 				// execute and open the first page
 				go func() {
-					err := c.Execute(query)
+					defer conn.Cancels.Clear(id, execID)
+
+					start := time.Now()
+					err := c.Execute(ctx, query)
+					status := "success"
+					if err != nil {
+						status = "error"
+					}
+					metrics.QueriesTotal.WithLabelValues(id, status).Inc()
+					metrics.QueryDuration.WithLabelValues(id).Observe(time.Since(start).Seconds())
 					if err != nil {
 						logger.Error(err.Error())
 						return
@@ -138,6 +317,26 @@ func main() {
 				return nil
 			})
 
+		p.HandleFunction(&plugin.FunctionOptions{Name: "Dbee_cancel"},
+			func(args []string) error {
+				method := "Dbee_cancel"
+				logger.Debug("calling " + method)
+				if len(args) < 1 {
+					logger.Error("not enough arguments passed to " + method)
+					return nil
+				}
+
+				id := args[0]
+
+				if !conn.Cancels.Cancel(id) {
+					logger.Debug("no running query to cancel for connection " + id)
+					return nil
+				}
+
+				logger.Debug(method + " returned successfully")
+				return nil
+			})
+
 		p.HandleFunction(&plugin.FunctionOptions{Name: "Dbee_history"},
 			func(args []string) error {
 				method := "Dbee_history"
@@ -220,6 +419,20 @@ func main() {
 				formatting := args[1]
 				file := args[2]
 
+				// optional format-specific options, e.g. target table for
+				// sql-insert or compression codec for parquet
+				formatOpt := ""
+				if len(args) > 3 {
+					formatOpt = args[3]
+				}
+
+				// optional 5th arg: SQL dialect for sql-insert, one of
+				// "postgres" (default), "mysql", "sqlite"
+				dialect := "postgres"
+				if len(args) > 4 && args[4] != "" {
+					dialect = args[4]
+				}
+
 				// Get the right connection
 				c, ok := connections[id]
 				if !ok {
@@ -235,6 +448,12 @@ func main() {
 					fmat = format.NewCSV()
 				case "table":
 					fmat = format.NewTable()
+				case "ndjson":
+					fmat = format.NewNDJSON()
+				case "parquet":
+					fmat = format.NewParquet(formatOpt)
+				case "sql-insert":
+					fmat = format.NewSQLInsert(formatOpt, dialect)
 				default:
 					logger.Error("save format: \"" + formatting + "\" is not supported")
 					return nil
@@ -242,15 +461,10 @@ func main() {
 
 				var out conn.Output
 				switch formatting {
-				case "json":
-					out = output.NewFile(file, fmat, logger)
-				case "csv":
-					out = output.NewFile(file, fmat, logger)
 				case "table":
 					out = output.NewYankRegister(v, fmat)
 				default:
-					logger.Error("save format: \"" + formatting + "\" is not supported")
-					return nil
+					out = output.NewFile(file, fmat, logger)
 				}
 				err := c.WriteCurrent(out)
 				if err != nil {
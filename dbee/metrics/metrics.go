@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// This file wires up the counters/histograms/gauges instrumenting
+// conn.Conn.Execute and the cache producer/page paths. They're exported so
+// conn and cache can call them directly without an import cycle back here.
+var (
+	QueriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "dbee",
+		Name:      "queries_total",
+		Help:      "Total number of queries executed, labeled by connection id and outcome.",
+	}, []string{"connection_id", "status"})
+
+	QueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "dbee",
+		Name:      "query_duration_seconds",
+		Help:      "Duration of conn.Conn.Execute calls, labeled by connection id.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"connection_id"})
+
+	CacheRowsProduced = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "dbee",
+		Name:      "cache_rows_produced_total",
+		Help:      "Total number of rows streamed into the cache across all records.",
+	})
+
+	CacheActiveRecords = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "dbee",
+		Name:      "cache_active_records",
+		Help:      "Number of result records still being streamed into the cache (not yet drained).",
+	})
+
+	CacheDrainDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "dbee",
+		Name:      "cache_drain_duration_seconds",
+		Help:      "Time from a record becoming active to it being fully drained.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	CachePageRequests = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "dbee",
+		Name:      "cache_page_requests_total",
+		Help:      "Total number of cache.page calls.",
+	})
+
+	CacheSpanDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "dbee",
+		Name:      "cache_span_duration_seconds",
+		Help:      "Duration of cache.Span calls, including any wait for drain.",
+		Buckets:   prometheus.DefBuckets,
+	})
+)
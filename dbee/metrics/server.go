@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Server wraps an http.Server serving /metrics, so it can be started and
+// stopped cleanly from the plugin's Dbee_start_metrics_server /
+// Dbee_stop_metrics_server handlers.
+type Server struct {
+	http *http.Server
+}
+
+// Start begins listening on addr (host:port, port may be 0 to pick a free
+// one) and serves promhttp.Handler() on /metrics. It returns the resolved
+// address once the listener is up.
+func Start(addr string) (*Server, string, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, "", err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	s := &Server{http: &http.Server{Handler: mux}}
+
+	go func() {
+		_ = s.http.Serve(ln)
+	}()
+
+	return s, ln.Addr().String(), nil
+}
+
+// Stop shuts the server down, waiting for in-flight scrapes to finish.
+func (s *Server) Stop(ctx context.Context) error {
+	return s.http.Shutdown(ctx)
+}
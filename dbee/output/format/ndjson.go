@@ -0,0 +1,68 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/kndndrj/nvim-dbee/dbee/models"
+)
+
+// ndjson formats a models.Result as newline-delimited JSON: one object per
+// row, keyed by the result header. WriteHeader/WriteRow/WriteFooter are
+// split out for readability, but Format still buffers the whole result
+// before returning it, same as the other formatters in this package.
+//
+// Dbee_save hands this formatter to output.NewFile like any other, and
+// cache.flush passes it the whole row slice at once, so rows are not
+// streamed to the file one at a time: an NDJSON export holds the complete
+// result and its encoded bytes in memory.
+type ndjson struct {
+	header models.Header
+}
+
+// NewNDJSON returns a formatter producing one JSON object per line.
+func NewNDJSON() *ndjson {
+	return &ndjson{}
+}
+
+func (f *ndjson) rowToObject(row models.Row) map[string]any {
+	obj := make(map[string]any, len(f.header))
+	for i, col := range f.header {
+		if i < len(row) {
+			obj[col] = row[i]
+		} else {
+			obj[col] = nil
+		}
+	}
+	return obj
+}
+
+func (f *ndjson) Format(result models.Result) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := f.WriteHeader(&buf, result.Header, result.Meta); err != nil {
+		return nil, err
+	}
+	for _, row := range result.Rows {
+		if err := f.WriteRow(&buf, row); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.WriteFooter(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (f *ndjson) WriteHeader(w io.Writer, header models.Header, meta models.Meta) error {
+	f.header = header
+	return nil
+}
+
+func (f *ndjson) WriteRow(w io.Writer, row models.Row) error {
+	return json.NewEncoder(w).Encode(f.rowToObject(row))
+}
+
+func (f *ndjson) WriteFooter(w io.Writer) error {
+	return nil
+}
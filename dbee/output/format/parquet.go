@@ -0,0 +1,121 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kndndrj/nvim-dbee/dbee/models"
+	"github.com/xitongsys/parquet-go-source/writerfile"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// parquetFormat writes a models.Result as a parquet file, inferring a
+// row-group schema from the result header and the column types reported in
+// its Meta. Parquet's column-oriented layout needs every row before it can
+// write row groups, so the whole result is buffered first, the same way
+// the plain JSON/CSV formatters are.
+type parquetFormat struct {
+	compression parquet.CompressionCodec
+}
+
+// parquetCodecs maps the codec names accepted as Dbee_save's format-option
+// argument to their parquet-go constants.
+var parquetCodecs = map[string]parquet.CompressionCodec{
+	"":       parquet.CompressionCodec_SNAPPY,
+	"snappy": parquet.CompressionCodec_SNAPPY,
+	"gzip":   parquet.CompressionCodec_GZIP,
+	"zstd":   parquet.CompressionCodec_ZSTD,
+	"none":   parquet.CompressionCodec_UNCOMPRESSED,
+}
+
+// NewParquet returns a formatter producing a parquet file, compressed with
+// codec (one of "snappy", "gzip", "zstd", "none"). An unrecognized codec
+// falls back to snappy.
+func NewParquet(codec string) *parquetFormat {
+	compression, ok := parquetCodecs[strings.ToLower(codec)]
+	if !ok {
+		compression = parquet.CompressionCodec_SNAPPY
+	}
+	return &parquetFormat{compression: compression}
+}
+
+// parquetSchema mirrors the shape parquet-go's writer.NewJSONWriter expects:
+// a tree of nodes, each carrying its field definition in a single "Tag" DSL
+// string (e.g. "name=x, type=INT64, repetitiontype=OPTIONAL").
+type parquetSchema struct {
+	Tag    string          `json:"Tag"`
+	Fields []parquetSchema `json:"Fields,omitempty"`
+}
+
+// schemaFor builds a parquet-go JSON schema from the result header, falling
+// back to the BYTE_ARRAY (string) type for any column whose type isn't one
+// we have a mapping for. It's marshaled with encoding/json rather than
+// string-spliced so a column name containing a quote can't produce invalid
+// JSON.
+func (f *parquetFormat) schemaFor(result models.Result) (string, error) {
+	fields := make([]parquetSchema, 0, len(result.Header))
+	for i, col := range result.Header {
+		pType := "BYTE_ARRAY"
+		if i < len(result.Meta.ColumnTypes) {
+			switch result.Meta.ColumnTypes[i] {
+			case "int", "integer", "bigint", "smallint":
+				pType = "INT64"
+			case "float", "double", "real", "numeric", "decimal":
+				pType = "DOUBLE"
+			case "bool", "boolean":
+				pType = "BOOLEAN"
+			}
+		}
+		fields = append(fields, parquetSchema{
+			Tag: fmt.Sprintf("name=%s, type=%s, repetitiontype=OPTIONAL", col, pType),
+		})
+	}
+
+	schema := parquetSchema{
+		Tag:    "name=row, repetitiontype=REQUIRED",
+		Fields: fields,
+	}
+
+	b, err := json.Marshal(schema)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (f *parquetFormat) Format(result models.Result) ([]byte, error) {
+	var buf bytes.Buffer
+
+	schema, err := f.schemaFor(result)
+	if err != nil {
+		return nil, fmt.Errorf("building parquet schema: %w", err)
+	}
+
+	fw := writerfile.NewWriterFile(&buf)
+	pw, err := writer.NewJSONWriter(schema, fw, 4)
+	if err != nil {
+		return nil, err
+	}
+	pw.CompressionType = f.compression
+
+	for _, row := range result.Rows {
+		obj := make(map[string]any, len(result.Header))
+		for i, col := range result.Header {
+			if i < len(row) {
+				obj[col] = row[i]
+			}
+		}
+		if err := pw.Write(obj); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := pw.WriteStop(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
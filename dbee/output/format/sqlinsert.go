@@ -0,0 +1,98 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/kndndrj/nvim-dbee/dbee/models"
+)
+
+// sqlInsert formats a models.Result as a sequence of dialect-correct
+// INSERT INTO ... VALUES (...) statements, one per row.
+type sqlInsert struct {
+	table   string
+	dialect string
+	header  models.Header
+}
+
+// NewSQLInsert returns a formatter producing INSERT statements against
+// table, quoting identifiers and literals for dialect.
+// Supported dialects: "postgres", "mysql", "sqlite".
+func NewSQLInsert(table string, dialect string) *sqlInsert {
+	return &sqlInsert{table: table, dialect: strings.ToLower(dialect)}
+}
+
+func (f *sqlInsert) quoteIdent(ident string) string {
+	switch f.dialect {
+	case "mysql":
+		return "`" + strings.ReplaceAll(ident, "`", "``") + "`"
+	default: // postgres, sqlite
+		return `"` + strings.ReplaceAll(ident, `"`, `""`) + `"`
+	}
+}
+
+func (f *sqlInsert) quoteLiteral(val any) string {
+	switch v := val.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if f.dialect == "postgres" {
+			return strconv.FormatBool(v)
+		}
+		if v {
+			return "1"
+		}
+		return "0"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", v)
+	case float32, float64:
+		return fmt.Sprintf("%v", v)
+	default:
+		s := fmt.Sprintf("%v", v)
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+}
+
+func (f *sqlInsert) Format(result models.Result) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := f.WriteHeader(&buf, result.Header, result.Meta); err != nil {
+		return nil, err
+	}
+	for _, row := range result.Rows {
+		if err := f.WriteRow(&buf, row); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.WriteFooter(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (f *sqlInsert) WriteHeader(w io.Writer, header models.Header, meta models.Meta) error {
+	f.header = header
+	return nil
+}
+
+func (f *sqlInsert) WriteRow(w io.Writer, row models.Row) error {
+	cols := make([]string, len(f.header))
+	for i, col := range f.header {
+		cols[i] = f.quoteIdent(col)
+	}
+
+	vals := make([]string, len(row))
+	for i, val := range row {
+		vals[i] = f.quoteLiteral(val)
+	}
+
+	_, err := fmt.Fprintf(w, "INSERT INTO %s (%s) VALUES (%s);\n",
+		f.quoteIdent(f.table), strings.Join(cols, ", "), strings.Join(vals, ", "))
+	return err
+}
+
+func (f *sqlInsert) WriteFooter(w io.Writer) error {
+	return nil
+}